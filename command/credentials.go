@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform/command/cliconfig"
+	"github.com/hashicorp/terraform/svchost/auth"
+)
+
+// credentialsSource assembles the layered CredentialsSource used by the
+// login and logout commands: TF_TOKEN_... environment variables take
+// priority over a configured credentials_helper, which in turn takes
+// priority over the on-disk credentials file at outputFile. noHelper (set
+// by -no-helper) forces storage into the file even when a helper is
+// configured.
+//
+// sinkName describes, for UI purposes, where newly-obtained credentials
+// will actually be written.
+func (m *Meta) credentialsSource(outputFile string, noHelper bool) (source auth.CredentialsSource, sinkName string) {
+	fileSource := auth.FileCredentialsSource(outputFile)
+	sources := []auth.CredentialsSource{auth.EnvCredentialsSource()}
+
+	if !noHelper {
+		if program, name, args, ok := m.findCredentialsHelper(); ok {
+			helperSource := auth.HelperProgramCredentialsSource(program, args...)
+			sources = append(sources, helperSource, fileSource)
+			return auth.NewCredentialsSources(sources...), fmt.Sprintf("the %q credentials helper", name)
+		}
+	}
+
+	sources = append(sources, fileSource)
+	return auth.NewCredentialsSources(sources...), outputFile
+}
+
+// findCredentialsHelper locates the external program backing a
+// credentials_helper block configured in the CLI config, searching first
+// the plugin cache directory and then PATH for a program named
+// "terraform-credentials-<name>", following the same resolution Terraform
+// uses to find provider plugins. The returned args are the helper block's
+// configured Args, to be passed through to the program on every invocation.
+func (m *Meta) findCredentialsHelper() (program string, name string, args []string, ok bool) {
+	var helper *cliconfig.ConfigCredentialsHelper
+	for helperName, h := range m.CredentialsHelpersConfig {
+		name, helper = helperName, h
+		break // only one credentials_helper may be configured at a time
+	}
+	if helper == nil {
+		return "", "", nil, false
+	}
+
+	progName := "terraform-credentials-" + name
+	if m.PluginCacheDir != "" {
+		candidate := filepath.Join(m.PluginCacheDir, progName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, name, helper.Args, true
+		}
+	}
+	if path, err := exec.LookPath(progName); err == nil {
+		return path, name, helper.Args, true
+	}
+
+	return "", name, nil, false
+}
@@ -1,12 +1,28 @@
 package command
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+	"github.com/hashicorp/terraform/svchost/disco"
 	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/pkg/browser"
+	"golang.org/x/oauth2"
 )
 
 // LoginCommand is a Command implementation that runs an interactive login
@@ -25,7 +41,15 @@ func (c *LoginCommand) Run(args []string) int {
 
 	cmdFlags := c.Meta.defaultFlagSet("login")
 	var intoFile string
+	var noHelper bool
+	var tokenArg string
+	var tokenFile string
+	var jsonOutput bool
 	cmdFlags.StringVar(&intoFile, "into-file", "", "set the file that the credentials will be appended to")
+	cmdFlags.BoolVar(&noHelper, "no-helper", false, "store the token in the credentials file even if a credentials_helper is configured")
+	cmdFlags.StringVar(&tokenArg, "token", "", "use the given token instead of running the interactive OAuth flow; use \"-\" to read it from stdin")
+	cmdFlags.StringVar(&tokenFile, "token-file", "", "use the token in the given file instead of running the interactive OAuth flow")
+	cmdFlags.BoolVar(&jsonOutput, "json", false, "emit the result as machine-readable JSON instead of human-readable output")
 	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := cmdFlags.Parse(args); err != nil {
 		return 1
@@ -38,6 +62,17 @@ func (c *LoginCommand) Run(args []string) int {
 		cmdFlags.Usage()
 		return 1
 	}
+	if tokenArg != "" && tokenFile != "" {
+		c.Ui.Error("The -token and -token-file options are mutually exclusive.")
+		cmdFlags.Usage()
+		return 1
+	}
+	nonInteractive := tokenArg != "" || tokenFile != ""
+	if jsonOutput && !nonInteractive {
+		c.Ui.Error("The -json option requires -token or -token-file, since it only applies to non-interactive logins.")
+		cmdFlags.Usage()
+		return 1
+	}
 
 	var diags tfdiags.Diagnostics
 
@@ -54,6 +89,8 @@ func (c *LoginCommand) Run(args []string) int {
 			"Invalid hostname",
 			fmt.Sprintf("The given hostname %q is not valid: %s.", givenHostname, err.Error()),
 		))
+		c.showDiagnostics(diags)
+		return 1
 	}
 
 	// From now on, since we've validated the given hostname, we should use
@@ -65,7 +102,7 @@ func (c *LoginCommand) Run(args []string) int {
 	if err != nil {
 		diags = diags.Append(tfdiags.Sourceless(
 			tfdiags.Error,
-			"Service discovery failed for"+dispHostname,
+			"Service discovery failed for "+dispHostname,
 
 			// Contrary to usual Go idiom, the Discover function returns
 			// full sentences with initial capitalization in its error messages,
@@ -74,13 +111,469 @@ func (c *LoginCommand) Run(args []string) int {
 			// with our usual error reporting standards.
 			err.Error()+".",
 		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+
+	outputFile := intoFile
+	if outputFile == "" {
+		outputFile = c.defaultOutputFile()
+		if outputFile == "" {
+			diags = diags.Append(tfdiags.Sourceless(
+				tfdiags.Error,
+				"Cannot determine a default credentials file",
+				"Terraform could not determine your home directory, so -into-file must be used to specify where to save the retrieved credentials.",
+			))
+			c.showDiagnostics(diags)
+			return 1
+		}
+	}
+
+	credsSource, sinkName := c.credentialsSource(outputFile, noHelper)
+
+	if nonInteractive {
+		return c.runNonInteractive(hostname, dispHostname, host, credsSource, sinkName, tokenArg, tokenFile, jsonOutput)
+	}
+
+	if !c.askApproval(dispHostname, sinkName) {
+		c.Ui.Info("Login cancelled.")
+		return 1
+	}
+
+	token, tokenDiags := c.retrieveToken(hostname, dispHostname, host)
+	diags = diags.Append(tokenDiags)
+	if tokenDiags.HasErrors() {
+		c.showDiagnostics(diags)
+		return 1
+	}
+
+	if err := credsSource.StoreForHost(hostname, auth.HostCredentialsToken(token.AccessToken)); err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to save credentials",
+			fmt.Sprintf("Terraform could not write the credentials for %s: %s.", dispHostname, err),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf(
+		c.Colorize().Color("[green]\nSuccess! Terraform has obtained and saved an API token.[reset]\n\n"+
+			"The token was saved in %s and will be used for any future Terraform\n"+
+			"command that must make authenticated requests to %s."),
+		sinkName, dispHostname,
+	))
+
+	c.showMOTD(host, dispHostname, token.AccessToken)
+
+	return 0
+}
+
+// showMOTD looks up the motd.v1 service advertised by host, fetches it
+// using the newly-obtained token, and renders it as a welcome banner. Hosts
+// that don't advertise a MOTD service, or that fail to respond to it, still
+// get a generic confirmation so the overall login still reads as a success.
+func (c *LoginCommand) showMOTD(host *disco.Host, dispHostname, token string) {
+	motdURL, err := host.ServiceURL("motd.v1")
+	if err != nil {
+		c.Ui.Output(fmt.Sprintf("\nLogged in to %s.", dispHostname))
+		return
+	}
+
+	req, err := http.NewRequest("GET", motdURL.String(), nil)
+	if err != nil {
+		c.Ui.Output(fmt.Sprintf("\nLogged in to %s.", dispHostname))
+		return
+	}
+	auth.HostCredentialsToken(token).PrepareRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.Ui.Output(fmt.Sprintf("\nLogged in to %s.", dispHostname))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.Ui.Output(fmt.Sprintf("\nLogged in to %s.", dispHostname))
+		return
+	}
+
+	var motd struct {
+		Message string `json:"msg"`
+		URL     string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&motd); err != nil || motd.Message == "" {
+		c.Ui.Output(fmt.Sprintf("\nLogged in to %s.", dispHostname))
+		return
+	}
+
+	banner := motd.Message
+	if motd.URL != "" {
+		banner += "\n\n" + motd.URL
+	}
+	c.Ui.Output(c.Colorize().Color("[bold]\n" + banner + "[reset]"))
+}
+
+// loginResult is the shape of the -json output for non-interactive logins,
+// intended for consumption by scripts running in CI pipelines that cannot
+// run the interactive OAuth flow.
+type loginResult struct {
+	Hostname string `json:"hostname"`
+	StoredIn string `json:"stored_in"`
+	Valid    bool   `json:"valid"`
+
+	// ExpiresAt is always empty: a token passed via -token or -token-file
+	// is an opaque bearer token, and neither the tfe.v2 account-details
+	// endpoint nor motd.v1 (the endpoints we validate it against) report an
+	// expiry for it. The field is still emitted, rather than omitted, so
+	// that consumers parsing the documented schema always find the key.
+	ExpiresAt string `json:"expires_at"`
+}
+
+// runNonInteractive handles the -token/-token-file code path: it skips the
+// browser-based OAuth flow entirely, validates the given token against the
+// host, and either stores it or reports a structured JSON result.
+func (c *LoginCommand) runNonInteractive(hostname svchost.Hostname, dispHostname string, host *disco.Host, credsSource auth.CredentialsSource, sinkName, tokenArg, tokenFile string, jsonOutput bool) int {
+	var diags tfdiags.Diagnostics
+
+	token, err := readNonInteractiveToken(tokenArg, tokenFile)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to read token",
+			err.Error(),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+
+	valid, err := validateToken(host, dispHostname, token)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to validate token",
+			fmt.Sprintf("Terraform could not validate the given token against %s: %s.", dispHostname, err),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+	if !valid {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Invalid token",
+			fmt.Sprintf("%s rejected the given token.", dispHostname),
+		))
+		c.showDiagnostics(diags)
+		return 1
 	}
 
-	fmt.Printf("Host is %#v\n", host)
+	if err := credsSource.StoreForHost(hostname, auth.HostCredentialsToken(token)); err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to save credentials",
+			fmt.Sprintf("Terraform could not write the credentials for %s: %s.", dispHostname, err),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
 
+	if jsonOutput {
+		result := loginResult{
+			Hostname: dispHostname,
+			StoredIn: sinkName,
+			Valid:    valid,
+		}
+		out, err := json.Marshal(result)
+		if err != nil {
+			diags = diags.Append(tfdiags.Sourceless(tfdiags.Error, "Failed to produce JSON output", err.Error()))
+			c.showDiagnostics(diags)
+			return 1
+		}
+		c.Ui.Output(string(out))
+		return 0
+	}
+
+	c.Ui.Output(fmt.Sprintf("Success! The token for %s was validated and saved in %s.", dispHostname, sinkName))
 	return 0
 }
 
+// readNonInteractiveToken reads the token supplied via -token or -token-file.
+// "-token -" reads the token from stdin, trimming the trailing newline a
+// shell pipeline is likely to add.
+func readNonInteractiveToken(tokenArg, tokenFile string) (string, error) {
+	switch {
+	case tokenArg == "-":
+		scanner := bufio.NewScanner(os.Stdin)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", fmt.Errorf("failed to read token from stdin: %s", err)
+			}
+			return "", fmt.Errorf("no token was given on stdin")
+		}
+		return strings.TrimSpace(scanner.Text()), nil
+	case tokenArg != "":
+		return tokenArg, nil
+	case tokenFile != "":
+		content, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %s", tokenFile, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	default:
+		return "", fmt.Errorf("no token was given")
+	}
+}
+
+// validateToken checks that token is accepted by host, preferring the
+// tfe.v2 service's /account/details endpoint (the richest signal, since it
+// confirms the token identifies an actual account) and falling back to the
+// motd.v1 service, which merely confirms the token is accepted as bearer
+// auth by some endpoint on the host.
+func validateToken(host *disco.Host, dispHostname, token string) (bool, error) {
+	if accountsURL, err := host.ServiceURL("tfe.v2"); err == nil {
+		u := *accountsURL
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/account/details"
+		if ok, err := probeAuthenticatedURL(u.String(), token); err == nil {
+			return ok, nil
+		}
+	}
+
+	if motdURL, err := host.ServiceURL("motd.v1"); err == nil {
+		return probeAuthenticatedURL(motdURL.String(), token)
+	}
+
+	return false, fmt.Errorf("%s does not advertise an endpoint Terraform can use to validate a token", dispHostname)
+}
+
+// probeAuthenticatedURL makes a bearer-authenticated GET request to url and
+// reports whether the server considered the token valid.
+func probeAuthenticatedURL(url, token string) (bool, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	auth.HostCredentialsToken(token).PrepareRequest(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return true, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+}
+
+// askApproval shows a consent prompt describing where the obtained
+// credentials will be stored and requires the user to confirm before the
+// token is requested. Service discovery for the host has already been done
+// by the time this is called.
+func (c *LoginCommand) askApproval(dispHostname, sinkName string) bool {
+	c.Ui.Output(fmt.Sprintf(
+		"Terraform will request an API token for %s using your browser.\n\n"+
+			"If login is successful, Terraform will store the token in\n"+
+			"%s for use by subsequent commands.\n",
+		dispHostname, sinkName,
+	))
+
+	v, err := c.Ui.Ask("Do you want to proceed? (y/n) ")
+	if err != nil {
+		return false
+	}
+	v = strings.ToLower(strings.TrimSpace(v))
+	return v == "y" || v == "yes"
+}
+
+// retrieveToken runs either the OAuth 2.0 authorization code grant with
+// PKCE, or, as a fallback for hosts whose login.v1 service doesn't register
+// any redirect ports (app.terraform.io only, as far as we know), the
+// resource owner password credentials grant, and returns the obtained
+// bearer token.
+func (c *LoginCommand) retrieveToken(hostname svchost.Hostname, dispHostname string, host *disco.Host) (*oauth2.Token, tfdiags.Diagnostics) {
+	var diags tfdiags.Diagnostics
+
+	clientConfig, err := host.ServiceOAuthClient("login.v1")
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Host does not support login",
+			fmt.Sprintf("%s does not provide a login.v1 service, so Terraform cannot automatically obtain an API token for it.", dispHostname),
+		))
+		return nil, diags
+	}
+
+	// A login.v1 service that doesn't register any redirect ports has no
+	// way to receive our local callback, so it can't support the
+	// authorization code grant with PKCE. app.terraform.io is the only
+	// host we know of in that situation, and it supports the resource
+	// owner password credentials grant as a fallback; any other host is
+	// simply not loggable-into.
+	if clientConfig.Ports.Min == 0 && clientConfig.Ports.Max == 0 {
+		if hostname == svchost.Hostname("app.terraform.io") {
+			return c.passwordGrant(dispHostname)
+		}
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Host does not support login",
+			fmt.Sprintf("%s's login.v1 service does not support a grant type Terraform knows how to use.", dispHostname),
+		))
+		return nil, diags
+	}
+
+	verifier, err := generateCodeVerifier()
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to generate PKCE code verifier",
+			err.Error(),
+		))
+		return nil, diags
+	}
+	challenge := codeChallengeS256(verifier)
+
+	state, err := generateState()
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to generate OAuth state",
+			err.Error(),
+		))
+		return nil, diags
+	}
+
+	listener, redirectURL, err := listenOnRegisteredPort(clientConfig.Ports)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to start local callback server",
+			fmt.Sprintf("Terraform could not listen on any of the ports registered by %s: %s.", dispHostname, err),
+		))
+		return nil, diags
+	}
+	defer listener.Close()
+
+	oauthConfig := &oauth2.Config{
+		ClientID:    clientConfig.ID,
+		Endpoint:    oauth2.Endpoint{AuthURL: clientConfig.Authz.String(), TokenURL: clientConfig.Token.String()},
+		RedirectURL: redirectURL,
+		Scopes:      clientConfig.Scopes,
+	}
+
+	authCodeURL := oauthConfig.AuthCodeURL(
+		state,
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+
+	c.Ui.Output(fmt.Sprintf("Terraform must now open a web browser to the login page for %s.\n", dispHostname))
+	if err := browser.OpenURL(authCodeURL); err != nil {
+		c.Ui.Output("Terraform was unable to automatically open your browser. Please open the following URL in a browser to log in:\n")
+		c.Ui.Output("    " + authCodeURL + "\n")
+	} else {
+		c.Ui.Output("If the browser does not open automatically, please open the following URL in a browser to log in:\n")
+		c.Ui.Output("    " + authCodeURL + "\n")
+	}
+
+	code, err := waitForCallback(listener, state)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Login failed",
+			err.Error(),
+		))
+		return nil, diags
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to exchange authorization code for a token",
+			err.Error(),
+		))
+		return nil, diags
+	}
+
+	return token, diags
+}
+
+// passwordGrant implements the fallback login flow for app.terraform.io,
+// which (unlike most hosts implementing login.v1) also supports the OAuth
+// 2.0 resource owner password credentials grant, including an optional
+// second factor.
+func (c *LoginCommand) passwordGrant(dispHostname string) (*oauth2.Token, tfdiags.Diagnostics) {
+	var diags tfdiags.Diagnostics
+
+	username, err := c.Ui.Ask("Username: ")
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(tfdiags.Error, "Failed to read username", err.Error()))
+		return nil, diags
+	}
+	password, err := c.Ui.AskSecret("Password: ")
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(tfdiags.Error, "Failed to read password", err.Error()))
+		return nil, diags
+	}
+	otp, err := c.Ui.Ask("Two-factor code (leave blank if not enabled): ")
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(tfdiags.Error, "Failed to read two-factor code", err.Error()))
+		return nil, diags
+	}
+
+	form := url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+	}
+	if otp != "" {
+		form.Set("otp", otp)
+	}
+
+	resp, err := http.PostForm(disco.OAuthOwnerPasswordGrant, form)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to authenticate with "+dispHostname,
+			err.Error(),
+		))
+		return nil, diags
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to authenticate with "+dispHostname,
+			fmt.Sprintf("the server returned HTTP status %s", resp.Status),
+		))
+		return nil, diags
+	}
+
+	var respBody struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to authenticate with "+dispHostname,
+			fmt.Sprintf("the server's response could not be parsed: %s", err),
+		))
+		return nil, diags
+	}
+
+	return &oauth2.Token{AccessToken: respBody.AccessToken, TokenType: respBody.TokenType}, diags
+}
+
 // Help implements cli.Command.
 func (c *LoginCommand) Help() string {
 	defaultFile := c.defaultOutputFile()
@@ -111,6 +604,22 @@ Options:
   -into-file=....     Override which file the credentials block will be written
                       to. If this file already exists then it must have valid
                       HCL syntax and Terraform will update it in-place.
+
+  -no-helper          Store the token in the credentials file even if a
+                      credentials_helper is configured.
+
+  -token=....         Skip the interactive OAuth flow and use the given
+                      token instead, after validating it. Use "-token=-" to
+                      read the token from stdin. Useful in CI pipelines.
+
+  -token-file=....    Like -token, but reads the token from the given file.
+
+  -json               Emit the result as machine-readable JSON instead of
+                      human-readable output. Requires -token or -token-file,
+                      since it only applies to non-interactive logins. The
+                      output always includes an "expires_at" key, which is
+                      empty because bearer tokens carry no expiry Terraform
+                      can discover.
 `, defaultFile)
 	return strings.TrimSpace(helpText)
 }
@@ -126,3 +635,95 @@ func (c *LoginCommand) defaultOutputFile() string {
 	}
 	return filepath.Join(c.CLIConfigDir, "credentials.tfrc")
 }
+
+// generateCodeVerifier produces a random PKCE code verifier, as described
+// in RFC 7636 section 4.1: a 43- to 128-character string drawn from the
+// unreserved URL characters [A-Z] [a-z] [0-9] "-" "." "_" "~".
+func generateCodeVerifier() (string, error) {
+	const verifierBytes = 32 // 32 random bytes -> 43 base64url characters once encoded
+	buf := make([]byte, verifierBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// codeChallengeS256 derives the PKCE code_challenge from a code_verifier
+// using the S256 transformation: base64url(sha256(verifier)).
+func codeChallengeS256(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// generateState produces a random value to bind the authorization request
+// to its callback, mitigating CSRF against the redirect URI.
+func generateState() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// listenOnRegisteredPort opens a TCP listener on the first available port
+// out of those the OAuth client is registered to use for its redirect URI,
+// returning both the listener and the corresponding
+// "http://localhost:PORT/login" redirect URL.
+func listenOnRegisteredPort(ports disco.PortRange) (net.Listener, string, error) {
+	var lastErr error
+	for port := ports.Min; port <= ports.Max; port++ {
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return l, fmt.Sprintf("http://localhost:%d/login", port), nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no ports registered")
+	}
+	return nil, "", lastErr
+}
+
+// waitForCallback runs a short-lived HTTP server on the given listener to
+// receive the OAuth 2.0 authorization code redirect, verifying that the
+// "state" parameter matches wantState before returning the code.
+func waitForCallback(listener net.Listener, wantState string) (string, error) {
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if errMsg := q.Get("error"); errMsg != "" {
+				errCh <- fmt.Errorf("authorization server returned error: %s", errMsg)
+				http.Error(w, "Login failed. You may close this tab.", http.StatusBadRequest)
+				return
+			}
+			if q.Get("state") != wantState {
+				errCh <- fmt.Errorf("state parameter in callback did not match the expected value")
+				http.Error(w, "Login failed. You may close this tab.", http.StatusBadRequest)
+				return
+			}
+			code := q.Get("code")
+			if code == "" {
+				errCh <- fmt.Errorf("no authorization code was returned")
+				http.Error(w, "Login failed. You may close this tab.", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, "Login successful. You may close this tab and return to the terminal.")
+			codeCh <- code
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	select {
+	case code := <-codeCh:
+		return code, nil
+	case err := <-errCh:
+		return "", err
+	case <-time.After(5 * time.Minute):
+		return "", fmt.Errorf("timed out waiting for the browser login to complete")
+	}
+}
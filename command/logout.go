@@ -0,0 +1,207 @@
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// LogoutCommand is a Command implementation that removes stored credentials
+// for a remote service host, undoing what LoginCommand did.
+type LogoutCommand struct {
+	Meta
+}
+
+// Run implements cli.Command.
+func (c *LogoutCommand) Run(args []string) int {
+	args, err := c.Meta.process(args, false)
+	if err != nil {
+		return 1
+	}
+
+	cmdFlags := c.Meta.defaultFlagSet("logout")
+	var fromFile string
+	cmdFlags.StringVar(&fromFile, "from-file", "", "set the file that the credentials will be removed from")
+	cmdFlags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := cmdFlags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = cmdFlags.Args()
+	if len(args) > 1 {
+		c.Ui.Error(
+			"The logout command expects at most one argument: the host to log out of.")
+		cmdFlags.Usage()
+		return 1
+	}
+
+	var diags tfdiags.Diagnostics
+
+	givenHostname := "app.terraform.io"
+	if len(args) != 0 {
+		givenHostname = args[0]
+	}
+
+	dispHostname := svchost.ForDisplay(givenHostname)
+	hostname, err := svchost.ForComparison(givenHostname)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Invalid hostname",
+			fmt.Sprintf("The given hostname %q is not valid: %s.", givenHostname, err.Error()),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+
+	inputFile := fromFile
+	if inputFile == "" {
+		inputFile = c.defaultOutputFile()
+		if inputFile == "" {
+			diags = diags.Append(tfdiags.Sourceless(
+				tfdiags.Error,
+				"Cannot determine a default credentials file",
+				"Terraform could not determine your home directory, so -from-file must be used to specify where the stored credentials are.",
+			))
+			c.showDiagnostics(diags)
+			return 1
+		}
+	}
+
+	credsSource, _ := c.credentialsSource(inputFile, false)
+
+	creds, err := credsSource.ForHost(hostname)
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to read credentials",
+			fmt.Sprintf("Terraform could not read the stored credentials for %s: %s.", dispHostname, err),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+	if creds == nil {
+		c.Ui.Output(fmt.Sprintf("No stored credentials for %s were found.", dispHostname))
+		return 0
+	}
+
+	if host, err := c.Services.Discover(hostname); err == nil {
+		if clientConfig, err := host.ServiceOAuthClient("login.v1"); err == nil {
+			if clientConfig.Revocation != nil {
+				// Best-effort: a revocation failure should not prevent us
+				// from removing the token locally, since the whole point of
+				// logout is to get rid of it.
+				if err := revokeToken(clientConfig.Revocation.String(), creds.Token()); err != nil {
+					c.Ui.Warn(fmt.Sprintf("Warning: failed to revoke the token with %s: %s.", dispHostname, err))
+				}
+			} else {
+				c.Ui.Output(fmt.Sprintf(
+					"Note: %s does not advertise a token revocation endpoint, so\n"+
+						"Terraform can only forget the token locally; it may still be valid on\n"+
+						"the host until it expires or is revoked by other means.", dispHostname))
+			}
+		}
+	}
+
+	removed, err := forgetStoredCredentials(credsSource, hostname, creds.Token())
+	if err != nil {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Error,
+			"Failed to remove credentials",
+			fmt.Sprintf("Terraform could not remove the stored credentials for %s: %s.", dispHostname, err),
+		))
+		c.showDiagnostics(diags)
+		return 1
+	}
+	if !removed {
+		c.Ui.Output(fmt.Sprintf(
+			"The credentials for %s came from a TF_TOKEN_... environment variable,\n"+
+				"which Terraform cannot remove. Unset it to stop using that token.",
+			dispHostname,
+		))
+		return 0
+	}
+
+	c.Ui.Output(fmt.Sprintf("Removed the stored API token for %s.", dispHostname))
+	return 0
+}
+
+// forgetStoredCredentials asks credsSource to forget its credentials for
+// host (which the caller has already confirmed exist) and reports whether
+// anything was actually removed. ForgetForHost returns nil both when it
+// successfully removes a credential and when none of its writable sources
+// (the credentials file or a configured helper) held one in the first
+// place -- which is exactly what happens when the only source that had
+// credentials was the read-only environment variable source. We
+// distinguish those cases by checking whether the same token is still
+// discoverable afterwards.
+func forgetStoredCredentials(credsSource auth.CredentialsSource, host svchost.Hostname, tokenBefore string) (bool, error) {
+	if err := credsSource.ForgetForHost(host); err != nil {
+		return false, err
+	}
+
+	after, err := credsSource.ForHost(host)
+	if err != nil {
+		return false, err
+	}
+
+	// If the same credentials are still there after forgetting, the only
+	// source that had them (e.g. TF_TOKEN_...) doesn't support removal.
+	if after != nil && after.Token() == tokenBefore {
+		return false, nil
+	}
+	return true, nil
+}
+
+// revokeToken calls the given login.v1 revocation endpoint with the token
+// to be revoked, per the OAuth 2.0 Token Revocation spec (RFC 7009).
+func revokeToken(revokeURL, token string) error {
+	resp, err := http.PostForm(revokeURL, url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("server returned HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+// Help implements cli.Command.
+func (c *LogoutCommand) Help() string {
+	helpText := `
+Usage: terraform logout [hostname]
+
+  Removes locally-stored credentials for the given hostname, revoking them
+  with the host first if its login.v1 service advertises a revocation
+  endpoint. Hosts that don't advertise one will still have the token
+  forgotten locally, but it may remain valid on the host until it expires.
+
+  If no hostname is provided, the default hostname is app.terraform.io.
+
+  Logout also invokes any configured credentials_helper so that it forgets
+  the token, in addition to removing it from the credentials file. Tokens
+  that came only from a TF_TOKEN_... environment variable cannot be
+  removed this way; unset the variable instead.
+
+Options:
+
+  -from-file=....     Override which file the credentials block will be
+                      removed from. Defaults to the same file that
+                      "terraform login" would write to.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis implements cli.Command.
+func (c *LogoutCommand) Synopsis() string {
+	return "Remove locally-stored credentials for a remote host"
+}
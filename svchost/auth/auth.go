@@ -0,0 +1,151 @@
+// Package auth defines abstractions for obtaining credentials for use when
+// talking to network services, such as the "login" and "logout" commands
+// (and the login-aware parts of the backend and provider source codepaths)
+// that authenticate against hosts discovered via svchost/disco.
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// HostCredentials represents a single set of credentials for a particular
+// hostname.
+type HostCredentials interface {
+	// PrepareRequest modifies the given request in-place to apply the
+	// credentials, e.g. by adding an Authorization header.
+	PrepareRequest(req *http.Request)
+
+	// Token returns the bearer token these credentials represent, if any.
+	// This exists mainly so that other commands (such as "logout") can
+	// present or revoke the token without needing to know the concrete
+	// credentials type in use.
+	Token() string
+}
+
+// HostCredentialsWritable is implemented by HostCredentials implementations
+// that can be serialized for storage by a CredentialsSource.
+type HostCredentialsWritable interface {
+	HostCredentials
+
+	// ToStore returns a representation of the credentials suitable for
+	// storing in a credentials source, such as a map with a "token" key
+	// for simple bearer-token credentials.
+	ToStore() map[string]interface{}
+}
+
+// HostCredentialsToken is the most common implementation of
+// HostCredentials: a simple bearer token presented via an Authorization
+// header.
+type HostCredentialsToken string
+
+// PrepareRequest implements HostCredentials.
+func (tc HostCredentialsToken) PrepareRequest(req *http.Request) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Authorization", "Bearer "+string(tc))
+}
+
+// Token implements HostCredentials.
+func (tc HostCredentialsToken) Token() string {
+	return string(tc)
+}
+
+// ToStore implements HostCredentialsWritable.
+func (tc HostCredentialsToken) ToStore() map[string]interface{} {
+	return map[string]interface{}{
+		"token": string(tc),
+	}
+}
+
+// CredentialsSource is an object that may be able to provide credentials
+// for a given hostname, and that can optionally also store and forget
+// credentials for the hosts it manages.
+//
+// Implementations that only support reading credentials (for example, one
+// that reads credentials from the TF_TOKEN_... environment variables) may
+// return ErrUnsupported from StoreForHost and ForgetForHost.
+type CredentialsSource interface {
+	// ForHost returns credentials for the given hostname, or nil if this
+	// source doesn't have credentials for that host. A non-nil error
+	// indicates that the source was unable to determine whether
+	// credentials are available.
+	ForHost(host svchost.Hostname) (HostCredentials, error)
+
+	// StoreForHost saves credentials for the given hostname, for sources
+	// that support updating credentials.
+	StoreForHost(host svchost.Hostname, credentials HostCredentialsWritable) error
+
+	// ForgetForHost removes any credentials for the given hostname, for
+	// sources that support updating credentials.
+	ForgetForHost(host svchost.Hostname) error
+}
+
+// ErrUnsupported is returned by StoreForHost or ForgetForHost for
+// credentials sources that are read-only.
+var ErrUnsupported = fmt.Errorf("credentials source does not support storing or forgetting credentials")
+
+// CredentialsSources combines zero or more CredentialsSource objects into a
+// single CredentialsSource that consults each of them in turn, in the order
+// given to NewCredentialsSources, returning the first non-nil result from
+// ForHost.
+//
+// StoreForHost is delegated to the highest-priority (first) source that does
+// not return ErrUnsupported, so that e.g. "terraform login" writes through a
+// configured credentials helper when one is present, and falls back to the
+// on-disk credentials file otherwise. ForgetForHost is instead passed to
+// every source that supports it.
+func NewCredentialsSources(sources ...CredentialsSource) CredentialsSource {
+	return credentialsSources(sources)
+}
+
+type credentialsSources []CredentialsSource
+
+func (s credentialsSources) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	for _, source := range s {
+		creds, err := source.ForHost(host)
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			return creds, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s credentialsSources) StoreForHost(host svchost.Hostname, credentials HostCredentialsWritable) error {
+	for _, source := range s {
+		err := source.StoreForHost(host, credentials)
+		if err == ErrUnsupported {
+			continue
+		}
+		return err
+	}
+	return ErrUnsupported
+}
+
+// ForgetForHost asks every source in turn to forget its credentials for
+// host, so that e.g. "terraform logout" clears both a configured
+// credentials helper and the on-disk credentials file. Sources that don't
+// support forgetting are skipped.
+func (s credentialsSources) ForgetForHost(host svchost.Hostname) error {
+	supported := false
+	for _, source := range s {
+		err := source.ForgetForHost(host)
+		if err == ErrUnsupported {
+			continue
+		}
+		supported = true
+		if err != nil {
+			return err
+		}
+	}
+	if !supported {
+		return ErrUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// staticTestSource is a minimal CredentialsSource used to exercise
+// credentialsSources' layering logic without depending on the filesystem
+// or environment.
+type staticTestSource struct {
+	token       string // "" means "no credentials for any host"
+	storeErr    error
+	forgetErr   error
+	stored      bool
+	forgotten   bool
+	unsupported bool
+}
+
+func (s *staticTestSource) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	if s.token == "" {
+		return nil, nil
+	}
+	return HostCredentialsToken(s.token), nil
+}
+
+func (s *staticTestSource) StoreForHost(host svchost.Hostname, credentials HostCredentialsWritable) error {
+	if s.unsupported {
+		return ErrUnsupported
+	}
+	s.stored = true
+	return s.storeErr
+}
+
+func (s *staticTestSource) ForgetForHost(host svchost.Hostname) error {
+	if s.unsupported {
+		return ErrUnsupported
+	}
+	s.forgotten = true
+	return s.forgetErr
+}
+
+func testHost(t *testing.T) svchost.Hostname {
+	t.Helper()
+	host, err := svchost.ForComparison("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+func TestCredentialsSources_ForHostPriority(t *testing.T) {
+	host := testHost(t)
+
+	first := &staticTestSource{token: "from-first"}
+	second := &staticTestSource{token: "from-second"}
+	combined := NewCredentialsSources(first, second)
+
+	creds, err := combined.ForHost(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.Token() != "from-first" {
+		t.Fatalf("ForHost = %v, want token from-first", creds)
+	}
+}
+
+func TestCredentialsSources_ForHostFallsThrough(t *testing.T) {
+	host := testHost(t)
+
+	empty := &staticTestSource{}
+	second := &staticTestSource{token: "from-second"}
+	combined := NewCredentialsSources(empty, second)
+
+	creds, err := combined.ForHost(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.Token() != "from-second" {
+		t.Fatalf("ForHost = %v, want token from-second", creds)
+	}
+}
+
+func TestCredentialsSources_ForHostNoneMatch(t *testing.T) {
+	host := testHost(t)
+
+	combined := NewCredentialsSources(&staticTestSource{}, &staticTestSource{})
+
+	creds, err := combined.ForHost(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds != nil {
+		t.Fatalf("ForHost = %v, want nil", creds)
+	}
+}
+
+func TestCredentialsSources_StoreUsesFirstSupportingSource(t *testing.T) {
+	host := testHost(t)
+
+	readOnly := &staticTestSource{unsupported: true}
+	writable := &staticTestSource{}
+	combined := NewCredentialsSources(readOnly, writable)
+
+	if err := combined.StoreForHost(host, HostCredentialsToken("tok")); err != nil {
+		t.Fatal(err)
+	}
+	if !writable.stored {
+		t.Error("expected the writable source to have been used")
+	}
+}
+
+func TestCredentialsSources_StoreAllUnsupported(t *testing.T) {
+	host := testHost(t)
+
+	combined := NewCredentialsSources(&staticTestSource{unsupported: true}, &staticTestSource{unsupported: true})
+
+	if err := combined.StoreForHost(host, HostCredentialsToken("tok")); err != ErrUnsupported {
+		t.Errorf("StoreForHost error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestCredentialsSources_ForgetAsksEverySupportingSource(t *testing.T) {
+	host := testHost(t)
+
+	helper := &staticTestSource{}
+	file := &staticTestSource{}
+	readOnly := &staticTestSource{unsupported: true}
+	combined := NewCredentialsSources(readOnly, helper, file)
+
+	if err := combined.ForgetForHost(host); err != nil {
+		t.Fatal(err)
+	}
+	if !helper.forgotten {
+		t.Error("expected the helper source to have been asked to forget")
+	}
+	if !file.forgotten {
+		t.Error("expected the file source to have been asked to forget")
+	}
+}
+
+func TestCredentialsSources_ForgetAllUnsupported(t *testing.T) {
+	host := testHost(t)
+
+	combined := NewCredentialsSources(&staticTestSource{unsupported: true})
+
+	if err := combined.ForgetForHost(host); err != ErrUnsupported {
+		t.Errorf("ForgetForHost error = %v, want ErrUnsupported", err)
+	}
+}
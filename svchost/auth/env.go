@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// envCredentialsSourcePrefix is the prefix of the environment variable name
+// that EnvCredentialsSource looks for, e.g. TF_TOKEN_app_terraform_io.
+const envCredentialsSourcePrefix = "TF_TOKEN_"
+
+// EnvCredentialsSource returns a CredentialsSource that reads bearer tokens
+// from TF_TOKEN_<hostname> environment variables.
+//
+// Because environment variable names cannot contain the "." and "-"
+// characters that commonly appear in hostnames, the hostname portion of the
+// variable name is decoded specially: a sequence of two underscores ("__")
+// represents a literal dash, and a single underscore ("_") represents a
+// dot. For example, TF_TOKEN_app_terraform_io maps to app.terraform.io.
+//
+// This source is always read-only: StoreForHost and ForgetForHost both
+// return ErrUnsupported, since modifying the calling process's environment
+// would not be useful or expected.
+func EnvCredentialsSource() CredentialsSource {
+	return envCredentialsSource{}
+}
+
+type envCredentialsSource struct{}
+
+func (s envCredentialsSource) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	for _, env := range os.Environ() {
+		eq := strings.IndexByte(env, '=')
+		if eq < 0 {
+			continue
+		}
+		name, value := env[:eq], env[eq+1:]
+		if !strings.HasPrefix(name, envCredentialsSourcePrefix) {
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		candidate, err := svchost.ForComparison(decodeEnvHostname(name[len(envCredentialsSourcePrefix):]))
+		if err != nil {
+			continue
+		}
+		if candidate == host {
+			return HostCredentialsToken(value), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s envCredentialsSource) StoreForHost(host svchost.Hostname, credentials HostCredentialsWritable) error {
+	return ErrUnsupported
+}
+
+func (s envCredentialsSource) ForgetForHost(host svchost.Hostname) error {
+	return ErrUnsupported
+}
+
+// decodeEnvHostname reverses the encoding terraform applies to a hostname
+// to fit it into an environment variable name: "__" becomes "-" and "_"
+// becomes ".".
+func decodeEnvHostname(encoded string) string {
+	var b strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		if encoded[i] == '_' {
+			if i+1 < len(encoded) && encoded[i+1] == '_' {
+				b.WriteByte('-')
+				i++
+				continue
+			}
+			b.WriteByte('.')
+			continue
+		}
+		b.WriteByte(encoded[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+func TestDecodeEnvHostname(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{"app_terraform_io", "app.terraform.io"},
+		{"example_com", "example.com"},
+		{"my__host_example_com", "my-host.example.com"},
+		{"a__b__c", "a-b-c"},
+		{"nodotsordashes", "nodotsordashes"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.encoded, func(t *testing.T) {
+			got := decodeEnvHostname(test.encoded)
+			if got != test.want {
+				t.Errorf("decodeEnvHostname(%q) = %q, want %q", test.encoded, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEnvCredentialsSource_ForHost(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("TF_TOKEN_app_terraform_io", "abc123")
+	os.Setenv("TF_TOKEN_my__host_example_com", "def456")
+	defer os.Clearenv()
+
+	source := EnvCredentialsSource()
+
+	t.Run("simple hostname", func(t *testing.T) {
+		host, err := svchost.ForComparison("app.terraform.io")
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, err := source.ForHost(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds == nil {
+			t.Fatal("expected credentials, got nil")
+		}
+		if got, want := creds.Token(), "abc123"; got != want {
+			t.Errorf("token = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("hostname with a dash", func(t *testing.T) {
+		host, err := svchost.ForComparison("my-host.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, err := source.ForHost(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds == nil {
+			t.Fatal("expected credentials, got nil")
+		}
+		if got, want := creds.Token(), "def456"; got != want {
+			t.Errorf("token = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("hostname with no matching variable", func(t *testing.T) {
+		host, err := svchost.ForComparison("unconfigured.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, err := source.ForHost(host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds != nil {
+			t.Errorf("expected no credentials, got %v", creds)
+		}
+	})
+}
+
+func TestEnvCredentialsSource_readOnly(t *testing.T) {
+	source := EnvCredentialsSource()
+	host, err := svchost.ForComparison("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := source.StoreForHost(host, HostCredentialsToken("x")); err != ErrUnsupported {
+		t.Errorf("StoreForHost error = %v, want ErrUnsupported", err)
+	}
+	if err := source.ForgetForHost(host); err != ErrUnsupported {
+		t.Errorf("ForgetForHost error = %v, want ErrUnsupported", err)
+	}
+}
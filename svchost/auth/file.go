@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hclwrite"
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FileCredentialsSource returns a CredentialsSource that reads and writes
+// "credentials" blocks in the given HCL file, such as the default
+// credentials.tfrc. Any other content already present in the file is
+// preserved.
+func FileCredentialsSource(filename string) CredentialsSource {
+	return fileCredentialsSource{filename: filename}
+}
+
+type fileCredentialsSource struct {
+	filename string
+}
+
+func (s fileCredentialsSource) parse() (*hclwrite.File, error) {
+	src, err := ioutil.ReadFile(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hclwrite.NewEmptyFile(), nil
+		}
+		return nil, err
+	}
+	f, diags := hclwrite.ParseConfig(src, s.filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return f, nil
+}
+
+func (s fileCredentialsSource) block(f *hclwrite.File, host svchost.Hostname) *hclwrite.Block {
+	for _, block := range f.Body().Blocks() {
+		if block.Type() != "credentials" {
+			continue
+		}
+		labels := block.Labels()
+		if len(labels) == 1 && labels[0] == string(host) {
+			return block
+		}
+	}
+	return nil
+}
+
+func (s fileCredentialsSource) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	f, err := s.parse()
+	if err != nil {
+		return nil, err
+	}
+	block := s.block(f, host)
+	if block == nil {
+		return nil, nil
+	}
+	attr := block.Body().GetAttribute("token")
+	if attr == nil {
+		return nil, nil
+	}
+	tokens := attr.Expr().BuildTokens(nil)
+	token, err := tokenLiteralString(tokens)
+	if err != nil {
+		return nil, nil
+	}
+	return HostCredentialsToken(token), nil
+}
+
+func (s fileCredentialsSource) StoreForHost(host svchost.Hostname, credentials HostCredentialsWritable) error {
+	f, err := s.parse()
+	if err != nil {
+		return err
+	}
+	body := f.Body()
+	if existing := s.block(f, host); existing != nil {
+		body.RemoveBlock(existing)
+	}
+
+	block := body.AppendNewBlock("credentials", []string{string(host)})
+	values := credentials.ToStore()
+	if token, ok := values["token"].(string); ok {
+		block.Body().SetAttributeValue("token", cty.StringVal(token))
+	}
+
+	return s.write(f)
+}
+
+func (s fileCredentialsSource) ForgetForHost(host svchost.Hostname) error {
+	f, err := s.parse()
+	if err != nil {
+		return err
+	}
+	block := s.block(f, host)
+	if block == nil {
+		return nil
+	}
+	f.Body().RemoveBlock(block)
+	return s.write(f)
+}
+
+// tokenLiteralString extracts the literal value of a simple quoted string
+// attribute, which is the only form the "token" attribute is ever written
+// in by Terraform itself. A token attribute set by hand to something more
+// complex (an interpolation, a concatenation, etc) is rejected.
+func tokenLiteralString(tokens hclwrite.Tokens) (string, error) {
+	raw := strings.TrimSpace(string(tokens.Bytes()))
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return "", fmt.Errorf("token attribute is not a simple string literal")
+	}
+	inner := raw[1 : len(raw)-1]
+	if strings.ContainsAny(inner, `"\`) || strings.Contains(inner, "${") || strings.Contains(inner, "%{") {
+		return "", fmt.Errorf("token attribute is not a simple string literal")
+	}
+	return inner, nil
+}
+
+func (s fileCredentialsSource) write(f *hclwrite.File) error {
+	tmp := s.filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, f.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filename)
+}
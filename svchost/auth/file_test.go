@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+func TestFileCredentialsSource_storeAndForgetPreservesOtherContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terraform-auth-file-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "credentials.tfrc")
+
+	const existingContent = `# a comment that should survive
+other_block "example" {
+  foo = "bar"
+}
+`
+	if err := ioutil.WriteFile(filename, []byte(existingContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	source := FileCredentialsSource(filename)
+
+	appHost, err := svchost.ForComparison("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHost, err := svchost.ForComparison("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := source.StoreForHost(appHost, HostCredentialsToken("abc123")); err != nil {
+		t.Fatalf("StoreForHost: %s", err)
+	}
+	if err := source.StoreForHost(otherHost, HostCredentialsToken("def456")); err != nil {
+		t.Fatalf("StoreForHost: %s", err)
+	}
+
+	contentAfterStore, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(contentAfterStore), "a comment that should survive") {
+		t.Errorf("pre-existing content was not preserved:\n%s", contentAfterStore)
+	}
+	if !strings.Contains(string(contentAfterStore), `foo = "bar"`) {
+		t.Errorf("pre-existing block was not preserved:\n%s", contentAfterStore)
+	}
+
+	creds, err := source.ForHost(appHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.Token() != "abc123" {
+		t.Fatalf("ForHost(appHost) = %v, want token abc123", creds)
+	}
+
+	// Storing again for the same host should replace, not duplicate, its
+	// credentials block.
+	if err := source.StoreForHost(appHost, HostCredentialsToken("updated")); err != nil {
+		t.Fatalf("StoreForHost (update): %s", err)
+	}
+	creds, err = source.ForHost(appHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.Token() != "updated" {
+		t.Fatalf("ForHost(appHost) after update = %v, want token updated", creds)
+	}
+
+	// Forgetting one host's credentials must not disturb the other host's
+	// credentials block or the unrelated content in the file.
+	if err := source.ForgetForHost(appHost); err != nil {
+		t.Fatalf("ForgetForHost: %s", err)
+	}
+
+	creds, err = source.ForHost(appHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds != nil {
+		t.Errorf("ForHost(appHost) after forget = %v, want nil", creds)
+	}
+
+	creds, err = source.ForHost(otherHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.Token() != "def456" {
+		t.Fatalf("ForHost(otherHost) after forgetting appHost = %v, want token def456", creds)
+	}
+
+	finalContent, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(finalContent), "a comment that should survive") {
+		t.Errorf("pre-existing content was lost after forget:\n%s", finalContent)
+	}
+}
+
+func TestFileCredentialsSource_forHostMissingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terraform-auth-file-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "does-not-exist.tfrc")
+
+	source := FileCredentialsSource(filename)
+	host, err := svchost.ForComparison("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := source.ForHost(host)
+	if err != nil {
+		t.Fatalf("ForHost on missing file: %s", err)
+	}
+	if creds != nil {
+		t.Errorf("ForHost on missing file = %v, want nil", creds)
+	}
+
+	if err := source.ForgetForHost(host); err != nil {
+		t.Errorf("ForgetForHost on missing file: %s", err)
+	}
+}
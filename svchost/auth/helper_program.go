@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// HelperProgramCredentialsSource returns a CredentialsSource that delegates
+// to an external program implementing the "credentials helper" protocol:
+//
+//   <program> get <hostname>      writes {"token":"..."} to stdout, or
+//                                  exits non-zero (with nothing on stdout)
+//                                  if it has no credentials for the host.
+//   <program> store <hostname>    reads a JSON object with a "token" field
+//                                  from stdin.
+//   <program> forget <hostname>   removes any stored credentials.
+//
+// program is expected to already be resolved to an executable path, such
+// as by searching PluginCacheDir and then PATH for a program named
+// "terraform-credentials-<name>".
+func HelperProgramCredentialsSource(program string, args ...string) CredentialsSource {
+	return helperProgramCredentialsSource{program: program, args: args}
+}
+
+type helperProgramCredentialsSource struct {
+	program string
+	args    []string
+}
+
+func (s helperProgramCredentialsSource) command(subcommand string, host svchost.Hostname) *exec.Cmd {
+	args := make([]string, 0, len(s.args)+2)
+	args = append(args, s.args...)
+	args = append(args, subcommand, string(host))
+	return exec.Command(s.program, args...)
+}
+
+func (s helperProgramCredentialsSource) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	cmd := s.command("get", host)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// A non-zero exit status means "no credentials for this host",
+			// which is not itself an error.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to run credentials helper %q: %s", s.program, err)
+	}
+
+	if stdout.Len() == 0 {
+		return nil, nil
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("credentials helper %q returned invalid JSON: %s", s.program, err)
+	}
+	if resp.Token == "" {
+		return nil, nil
+	}
+	return HostCredentialsToken(resp.Token), nil
+}
+
+func (s helperProgramCredentialsSource) StoreForHost(host svchost.Hostname, credentials HostCredentialsWritable) error {
+	payload, err := json.Marshal(credentials.ToStore())
+	if err != nil {
+		return fmt.Errorf("failed to serialize credentials for helper %q: %s", s.program, err)
+	}
+
+	cmd := s.command("store", host)
+	cmd.Stdin = bytes.NewReader(payload)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("credentials helper %q failed to store credentials: %s", s.program, err)
+	}
+	return nil
+}
+
+func (s helperProgramCredentialsSource) ForgetForHost(host svchost.Hostname) error {
+	cmd := s.command("forget", host)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("credentials helper %q failed to forget credentials: %s", s.program, err)
+	}
+	return nil
+}